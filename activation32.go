@@ -0,0 +1,173 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import "math"
+
+// Activation32 names a FunctionPair32 so that Neural32 can set it per layer
+// with SetActivation/SetOutputActivation and persist the choice with Save.
+// F and DF must be elementwise: use Softmax32, which Infer/InferWithT
+// special-case, for an activation that normalizes across a whole layer.
+type Activation32 struct {
+	name string
+	pair FunctionPair32
+}
+
+// String returns the activation's registry name.
+func (a Activation32) String() string {
+	return a.name
+}
+
+var (
+	// Sigmoid32 is the network's default activation.
+	Sigmoid32 = Activation32{"sigmoid", FunctionPair32{F: sigmoid32, T: identity, DF: dsigmoid32}}
+	// Tanh32 is the hyperbolic tangent activation set by UseTanh.
+	Tanh32 = Activation32{"tanh", FunctionPair32{F: tanh32, T: identity, DF: dtanh32}}
+	// ReLU32 is max(0, x).
+	ReLU32 = Activation32{"relu", FunctionPair32{F: relu32, T: identity, DF: drelu32}}
+	// LeakyReLU32 is ReLU with a small slope for negative inputs.
+	LeakyReLU32 = Activation32{"leaky_relu", FunctionPair32{F: leakyRelu32, T: identity, DF: dleakyRelu32}}
+	// ELU32 is the exponential linear unit.
+	ELU32 = Activation32{"elu", FunctionPair32{F: elu32, T: identity, DF: delu32}}
+	// SiLU32 is x*sigmoid(x), also known as the swish activation.
+	SiLU32 = Activation32{"silu", FunctionPair32{F: silu32, T: identity, DF: dsilu32}}
+	// Softmax32 selects a softmax output layer trained with cross-entropy
+	// loss. It is only valid as an argument to SetOutputActivation.
+	Softmax32 = Activation32{name: "softmax"}
+	// Identity32 is the pass-through activation EnableRegression sets on
+	// the output layer.
+	Identity32 = Activation32{"identity", FunctionPair32{F: identity, T: identity, DF: one}}
+)
+
+var activationRegistry32 = map[string]Activation32{
+	Sigmoid32.name:   Sigmoid32,
+	Tanh32.name:      Tanh32,
+	ReLU32.name:      ReLU32,
+	LeakyReLU32.name: LeakyReLU32,
+	ELU32.name:       ELU32,
+	SiLU32.name:      SiLU32,
+	Identity32.name:  Identity32,
+}
+
+const leakyReLUSlope32 = 0.01
+
+func relu32(x float32) float32 {
+	if x > 0 {
+		return x
+	}
+	return 0
+}
+
+// drelu32 is the derivative of relu32 with respect to the pre-activation
+// sum x (ReLU's derivative is not expressible in terms of its output alone,
+// since both x=0 and the whole negative half produce output 0).
+func drelu32(x float32) float32 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func leakyRelu32(x float32) float32 {
+	if x > 0 {
+		return x
+	}
+	return leakyReLUSlope32 * x
+}
+
+func dleakyRelu32(x float32) float32 {
+	if x > 0 {
+		return 1
+	}
+	return leakyReLUSlope32
+}
+
+func elu32(x float32) float32 {
+	if x > 0 {
+		return x
+	}
+	return float32(math.Exp(float64(x))) - 1
+}
+
+func delu32(x float32) float32 {
+	if x > 0 {
+		return 1
+	}
+	return elu32(x) + 1
+}
+
+func silu32(x float32) float32 {
+	return x * sigmoid32(x)
+}
+
+func dsilu32(x float32) float32 {
+	s := sigmoid32(x)
+	return s + x*s*(1-s)
+}
+
+// softmax32 normalizes sums into a probability distribution, written into
+// out. It subtracts the layer's maximum sum first so that the exponentials
+// stay in a numerically safe range.
+func softmax32(sums, out []float32) {
+	max := sums[0]
+	for _, s := range sums[1:] {
+		if s > max {
+			max = s
+		}
+	}
+
+	var total float32
+	for i, s := range sums {
+		e := float32(math.Exp(float64(s - max)))
+		out[i] = e
+		total += e
+	}
+
+	for i := range out {
+		out[i] /= total
+	}
+}
+
+// CrossEntropyLoss32 returns -sum(target*log(p)) for a softmax output
+// activations and its targets, the loss SetOutputActivation(Softmax32)
+// pairs with.
+func CrossEntropyLoss32(activations, targets []float32) float32 {
+	const epsilon = 1e-7
+
+	var loss float32
+	for i, t := range targets {
+		if t == 0 {
+			continue
+		}
+		p := activations[i]
+		if p < epsilon {
+			p = epsilon
+		}
+		loss -= t * float32(math.Log(float64(p)))
+	}
+	return loss
+}
+
+// SetActivation sets the activation function of layer layerIndex (0-based,
+// counting hidden layers before the output layer).
+func (n *Neural32) SetActivation(layerIndex int, act Activation32) {
+	n.Functions[layerIndex] = act.pair
+	n.activations[layerIndex] = act.name
+}
+
+// SetOutputActivation sets the activation of the final layer. Softmax32
+// additionally switches Infer/InferWithT to normalize the output layer as a
+// whole and BackPropagate to use the softmax+cross-entropy gradient.
+func (n *Neural32) SetOutputActivation(act Activation32) {
+	output := len(n.Functions) - 1
+	if act.name == Softmax32.name {
+		n.softmax = true
+		n.Functions[output] = FunctionPair32{F: identity, T: identity, DF: one}
+		n.activations[output] = act.name
+		return
+	}
+	n.softmax = false
+	n.SetActivation(output, act)
+}
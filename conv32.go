@@ -0,0 +1,245 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+// FilterSpec32 describes one width of 1-D convolution filter and how many
+// of them ConvLayer32 should learn.
+type FilterSpec32 struct {
+	// Width is the number of consecutive embedding vectors each filter
+	// slides over.
+	Width int
+	// Filters is how many independent filters of this width are learned.
+	Filters int
+	// Stride is how many positions the window advances between windows;
+	// it defaults to 1 when zero.
+	Stride int
+}
+
+// PoolKind32 selects how ConvLayer32 pools a filter's activations across
+// time. PoolMax32 is currently the only implementation.
+type PoolKind32 int
+
+const (
+	// PoolMax32 keeps, for each filter, the activation of the window that
+	// maximizes it (max-over-time pooling).
+	PoolMax32 PoolKind32 = iota
+)
+
+// ConvLayer32 is a single 1-D convolution over a sequence of embedding
+// vectors: for every window of Spec.Width consecutive vectors it computes
+// Spec.Filters dot products against learned filter weights, applies an
+// activation, and max-pools the result over time into a Spec.Filters
+// length vector.
+type ConvLayer32 struct {
+	Spec       FilterSpec32
+	EmbedDim   int
+	Activation Activation32
+
+	Weights [][]float32 // [filter][Width*EmbedDim], im2col-flattened
+	Bias    []float32   // [filter]
+	Changes [][]float32 // momentum buffer, shaped like Weights
+
+	windows   [][]float32 // [window] flattened input, cached by Forward
+	sums      [][]float32 // [window][filter] pre-activation sums
+	activated [][]float32 // [window][filter] post-activation values
+	argmax    []int       // [filter] index of the window that won the pool
+}
+
+// NewConvLayer32 allocates a ConvLayer32 over embedDim-wide embeddings.
+func NewConvLayer32(embedDim int, spec FilterSpec32, act Activation32) *ConvLayer32 {
+	if spec.Stride < 1 {
+		spec.Stride = 1
+	}
+
+	width := spec.Width * embedDim
+	weights := matrix32(spec.Filters, width)
+	for f := range weights {
+		for k := range weights[f] {
+			weights[f][k] = WeightInitializer32FanIn(width, spec.Filters)
+		}
+	}
+
+	return &ConvLayer32{
+		Spec:       spec,
+		EmbedDim:   embedDim,
+		Activation: act,
+		Weights:    weights,
+		Bias:       vector32(spec.Filters, 0),
+		Changes:    matrix32(spec.Filters, width),
+	}
+}
+
+func (c *ConvLayer32) windowCount(sequenceLen int) int {
+	n := 0
+	for start := 0; start+c.Spec.Width <= sequenceLen; start += c.Spec.Stride {
+		n++
+	}
+	return n
+}
+
+// Forward runs the convolution and max-pool over sequence, a slice of
+// EmbedDim-wide embedding vectors, and returns the pooled Spec.Filters
+// length output. It caches the window sums and pool decisions for the
+// following call to Backward.
+func (c *ConvLayer32) Forward(sequence [][]float32) []float32 {
+	n := c.windowCount(len(sequence))
+	c.windows = make([][]float32, n)
+	c.sums = make([][]float32, n)
+	c.activated = make([][]float32, n)
+
+	width := c.Spec.Width
+	w := 0
+	for start := 0; start+width <= len(sequence); start += c.Spec.Stride {
+		window := make([]float32, 0, width*c.EmbedDim)
+		for k := 0; k < width; k++ {
+			window = append(window, sequence[start+k]...)
+		}
+		c.windows[w] = window
+
+		sums := make([]float32, c.Spec.Filters)
+		activated := make([]float32, c.Spec.Filters)
+		for f := 0; f < c.Spec.Filters; f++ {
+			sums[f] = dot32(window, c.Weights[f]) + c.Bias[f]
+			activated[f] = c.Activation.pair.F(sums[f])
+		}
+		c.sums[w], c.activated[w] = sums, activated
+		w++
+	}
+
+	pooled := make([]float32, c.Spec.Filters)
+	argmax := make([]int, c.Spec.Filters)
+	for f := 0; f < c.Spec.Filters; f++ {
+		best, bestW := c.activated[0][f], 0
+		for wi := 1; wi < n; wi++ {
+			if c.activated[wi][f] > best {
+				best, bestW = c.activated[wi][f], wi
+			}
+		}
+		pooled[f], argmax[f] = best, bestW
+	}
+	c.argmax = argmax
+
+	return pooled
+}
+
+// Backward takes the negated gradient of the loss (the descent direction,
+// as in Context32.InputGrad) with respect to Forward's pooled output,
+// applies a classical-momentum weight update by adding it, and returns the
+// same negated gradient with respect to sequence so that an optional embedding table
+// upstream of the convolution can be trained too.
+func (c *ConvLayer32) Backward(sequence [][]float32, dPooled []float32, lRate, mFactor float32) [][]float32 {
+	dSequence := make([][]float32, len(sequence))
+	for i := range dSequence {
+		dSequence[i] = vector32(c.EmbedDim, 0)
+	}
+
+	width := c.Spec.Width
+	for f := 0; f < c.Spec.Filters; f++ {
+		wi := c.argmax[f]
+		dPre := dPooled[f] * c.Activation.pair.DF(c.sums[wi][f])
+
+		change := append([]float32(nil), c.windows[wi]...)
+		scal32(dPre, change)
+		scal32(mFactor, c.Changes[f])
+		axpy32(lRate, change, c.Changes[f])
+		axpy32(1, c.Changes[f], c.Weights[f])
+		c.Bias[f] += lRate * dPre
+
+		start := wi * c.Spec.Stride
+		for k := 0; k < width; k++ {
+			axpy32(dPre, c.Weights[f][k*c.EmbedDim:(k+1)*c.EmbedDim], dSequence[start+k])
+		}
+	}
+
+	return dSequence
+}
+
+// ConvNet32 stacks one ConvLayer32 per FilterSpec32 over a shared
+// embedding, max-pools and concatenates their outputs, and classifies the
+// result with a dense Neural32 — a shallow CNN suitable for tasks such as
+// sentence classification.
+type ConvNet32 struct {
+	EmbedDim  int
+	Convs     []*ConvLayer32
+	Pool      PoolKind32
+	Dense     *Neural32
+	Optimizer Optimizer32
+
+	context *Context32
+}
+
+// NewConvNet32 builds a ConvNet32 over embedDim-wide embeddings with one
+// ConvLayer32 per entry in filters, pooled with pool and concatenated into
+// a dense Neural32 with the given hidden/output layer sizes.
+func NewConvNet32(embedDim int, filters []FilterSpec32, pool PoolKind32, dense []int) *ConvNet32 {
+	convs := make([]*ConvLayer32, len(filters))
+	concat := 0
+	for i, spec := range filters {
+		convs[i] = NewConvLayer32(embedDim, spec, ReLU32)
+		concat += spec.Filters
+	}
+
+	d := &Neural32{}
+	d.Init(WeightInitializer32FanIn, append([]int{concat}, dense...)...)
+
+	optimizer := &MomentumOptimizer32{Factor: 0.4}
+	optimizer.Init(d.Layers)
+
+	return &ConvNet32{
+		EmbedDim:  embedDim,
+		Convs:     convs,
+		Pool:      pool,
+		Dense:     d,
+		Optimizer: optimizer,
+		context:   d.NewContext(),
+	}
+}
+
+func (m *ConvNet32) concat(sequence [][]float32) []float32 {
+	out := make([]float32, 0)
+	for _, conv := range m.Convs {
+		out = append(out, conv.Forward(sequence)...)
+	}
+	return out
+}
+
+// Forward runs every ConvLayer32 over sequence and classifies their
+// concatenated, pooled output with Dense.
+func (m *ConvNet32) Forward(sequence [][]float32) []float32 {
+	m.context.SetInput(m.concat(sequence))
+	m.context.Infer()
+	return m.context.GetOutput()
+}
+
+// Backward runs one training step: forward through the convolutions and
+// Dense, backpropagating target into Dense with Optimizer, and on into
+// each ConvLayer32. It returns the squared-error loss and the gradient
+// with respect to sequence, for training an optional embedding table
+// upstream of the convolutions.
+func (m *ConvNet32) Backward(sequence [][]float32, target []float32, lRate, mFactor float32) ([][]float32, float32) {
+	m.context.SetInput(m.concat(sequence))
+	m.context.TrackInputGrad = true
+	m.context.InferWithT()
+	loss := m.context.BackPropagate(target)
+	m.context.apply(m.Optimizer, lRate, 1, Regularizer32{})
+
+	dSequence := make([][]float32, len(sequence))
+	for i := range dSequence {
+		dSequence[i] = vector32(m.EmbedDim, 0)
+	}
+
+	offset := 0
+	for _, conv := range m.Convs {
+		dPooled := m.context.InputGrad[offset : offset+conv.Spec.Filters]
+		offset += conv.Spec.Filters
+
+		dConv := conv.Backward(sequence, dPooled, lRate, mFactor)
+		for i := range dSequence {
+			axpy32(1, dConv[i], dSequence[i])
+		}
+	}
+
+	return dSequence, loss
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestConvNet32(t *testing.T) {
+	rand.Seed(0)
+
+	const embedDim = 4
+	sequence := make([][]float32, 6)
+	for i := range sequence {
+		sequence[i] = vector32(embedDim, 0)
+		for k := range sequence[i] {
+			sequence[i][k] = random32(-1, 1)
+		}
+	}
+	target := []float32{1, 0}
+
+	net := NewConvNet32(embedDim, []FilterSpec32{
+		{Width: 2, Filters: 3},
+		{Width: 3, Filters: 2},
+	}, PoolMax32, []int{4, 4, 2})
+
+	if out := net.Forward(sequence); len(out) != len(target) {
+		t.Fatalf("got %d outputs, want %d", len(out), len(target))
+	}
+
+	var first, last float32
+	for i := 0; i < 200; i++ {
+		_, loss := net.Backward(sequence, target, 0.1, 0.4)
+		if i == 0 {
+			first = loss
+		}
+		last = loss
+	}
+
+	if last >= first {
+		t.Fatalf("loss did not decrease: first %v, last %v", first, last)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// GAOptions configures TrainGA. Zero values fall back to reasonable
+// defaults in setDefaults.
+type GAOptions struct {
+	// TournamentSize is how many individuals compete for each parent slot;
+	// larger values bias selection more strongly towards the fittest.
+	TournamentSize int
+	// Elite is how many of the fittest individuals survive to the next
+	// generation unchanged.
+	Elite int
+	// CrossoverRate is the per-gene probability that a child inherits a
+	// weight from its second parent rather than its first.
+	CrossoverRate float32
+	// MutationSigma is the standard deviation of the Gaussian noise added
+	// to every weight of a freshly bred child.
+	MutationSigma float32
+	// SigmaDecay multiplies MutationSigma after every generation, letting
+	// mutation shrink as the population converges.
+	SigmaDecay float32
+}
+
+func (o *GAOptions) setDefaults() {
+	if o.TournamentSize < 2 {
+		o.TournamentSize = 3
+	}
+	if o.Elite < 1 {
+		o.Elite = 1
+	}
+	if o.CrossoverRate == 0 {
+		o.CrossoverRate = 0.5
+	}
+	if o.MutationSigma == 0 {
+		o.MutationSigma = 0.1
+	}
+	if o.SigmaDecay == 0 {
+		o.SigmaDecay = 1
+	}
+}
+
+// TrainGA evolves a population of popSize networks sharing n's topology
+// using tournament selection, uniform crossover, and Gaussian mutation, as
+// an alternative to backprop for fitness functions that are not
+// differentiable (for example a game-playing win/loss/draw reward). Higher
+// fitness is better. It returns the fittest individual found across all
+// generations.
+func (n *Neural32) TrainGA(source func(iteration int) [][][]float32, popSize, generations int, fitness func(*Neural32, [][][]float32) float32, opts GAOptions) *Neural32 {
+	opts.setDefaults()
+
+	population := make([]*Neural32, popSize)
+	for p := range population {
+		individual := n.Clone()
+		individual.mutate(opts.MutationSigma)
+		population[p] = individual
+	}
+
+	sigma := opts.MutationSigma
+	var best *Neural32
+	var bestScore float32
+
+	for g := 0; g < generations; g++ {
+		batch := source(g)
+
+		scores := make([]float32, popSize)
+		for p, individual := range population {
+			scores[p] = fitness(individual, batch)
+		}
+
+		order := make([]int, popSize)
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool {
+			return scores[order[a]] > scores[order[b]]
+		})
+
+		if best == nil || scores[order[0]] > bestScore {
+			best, bestScore = population[order[0]], scores[order[0]]
+		}
+
+		next := make([]*Neural32, 0, popSize)
+		for e := 0; e < opts.Elite && e < popSize; e++ {
+			next = append(next, population[order[e]].Clone())
+		}
+		for len(next) < popSize {
+			parentA := tournamentSelect32(population, scores, opts.TournamentSize)
+			parentB := tournamentSelect32(population, scores, opts.TournamentSize)
+			child := parentA.crossover(parentB, opts.CrossoverRate)
+			child.mutate(sigma)
+			next = append(next, child)
+		}
+
+		population = next
+		sigma *= opts.SigmaDecay
+	}
+
+	return best.Clone()
+}
+
+func tournamentSelect32(population []*Neural32, scores []float32, size int) *Neural32 {
+	best := rand.Intn(len(population))
+	for k := 1; k < size; k++ {
+		i := rand.Intn(len(population))
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	return population[best]
+}
+
+// crossover returns a new Neural32 with n's topology whose weights are a
+// uniform crossover of n and other's corresponding [l][j][i] entries: each
+// weight is inherited from other with probability rate, and from n
+// otherwise.
+func (n *Neural32) crossover(other *Neural32, rate float32) *Neural32 {
+	child := n.Clone()
+	for l := range child.Weights {
+		for j := range child.Weights[l] {
+			for i := range child.Weights[l][j] {
+				if rand.Float32() < rate {
+					child.Weights[l][j][i] = other.Weights[l][j][i]
+				}
+			}
+		}
+	}
+	return child
+}
+
+// mutate perturbs every weight by sigma*N(0,1).
+func (n *Neural32) mutate(sigma float32) {
+	for l := range n.Weights {
+		for j := range n.Weights[l] {
+			for i := range n.Weights[l][j] {
+				n.Weights[l][j][i] += sigma * float32(rand.NormFloat64())
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// fitnessGA32 scores a network by how close it gets to the XOR patterns,
+// higher is better. It needs no gradient, which is the point of TrainGA:
+// the same scoring function works for losses that backprop cannot
+// differentiate, such as a game's win/loss/draw reward.
+func fitnessGA32(n *Neural32, batch [][][]float32) float32 {
+	context := n.NewContext()
+
+	var score float32
+	for _, p := range batch {
+		context.SetInput(p[0])
+		context.Infer()
+		for i, target := range p[1] {
+			e := target - context.GetOutput()[i]
+			score -= e * e
+		}
+	}
+	return score
+}
+
+func TestTrainGA(t *testing.T) {
+	rand.Seed(0)
+
+	config := func(neural *Neural32) {
+		neural.Init(WeightInitializer32FanIn, 2, 4, 1)
+	}
+	n := NewNeural32(config)
+
+	before := fitnessGA32(n, patterns)
+
+	best := n.TrainGA(source, 64, 200, fitnessGA32, GAOptions{
+		TournamentSize: 3,
+		Elite:          2,
+		CrossoverRate:  0.5,
+		MutationSigma:  0.2,
+		SigmaDecay:     0.98,
+	})
+
+	after := fitnessGA32(best, patterns)
+	if after <= before {
+		t.Fatalf("GA training did not improve fitness: before %v, after %v", before, after)
+	}
+}
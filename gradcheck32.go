@@ -0,0 +1,105 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+// GradCheckEntry is one weight's analytic-vs-numeric gradient comparison,
+// as returned by CheckGradient.
+type GradCheckEntry struct {
+	Layer, J, I       int
+	Analytic, Numeric float32
+	RelError          float32
+}
+
+// CheckGradient validates BackPropagate's analytic gradient against a
+// central finite-difference estimate, for every weight in n, and is a
+// testing helper: once a network grows new activations, regularizers, or
+// optimizers, a subtle bug in FunctionPair32.DF or the pre-activation
+// plumbing it relies on is otherwise invisible. It returns the largest
+// relative error seen across all weights alongside the per-weight detail.
+func (n *Neural32) CheckGradient(input, target []float32, eps float32) (float32, []GradCheckEntry) {
+	context := n.NewContext()
+	context.SetInput(input)
+	context.Infer()
+	context.BackPropagate(target)
+
+	details := make([]GradCheckEntry, 0, len(input)*len(target))
+	var maxRelErr float32
+
+	for l := range n.Weights {
+		for j := range n.Weights[l] {
+			for i := range n.Weights[l][j] {
+				analytic := context.Grads[l][j][i]
+				numeric := n.numericGradient32(l, j, i, input, target, eps)
+
+				denom := absFloat32(analytic) + absFloat32(numeric)
+				if denom < eps {
+					denom = eps
+				}
+				relErr := absFloat32(analytic-numeric) / denom
+
+				details = append(details, GradCheckEntry{
+					Layer:    l,
+					J:        j,
+					I:        i,
+					Analytic: analytic,
+					Numeric:  numeric,
+					RelError: relErr,
+				})
+
+				if relErr > maxRelErr {
+					maxRelErr = relErr
+				}
+			}
+		}
+	}
+
+	return maxRelErr, details
+}
+
+// numericGradient32 perturbs weight [l][j][i] by ±eps and returns the
+// central finite-difference estimate of halfLoss32's gradient with respect
+// to it, restoring the weight before returning.
+func (n *Neural32) numericGradient32(l, j, i int, input, target []float32, eps float32) float32 {
+	w := &n.Weights[l][j][i]
+	original := *w
+
+	*w = original + eps
+	plus := halfLoss32(n, input, target)
+
+	*w = original - eps
+	minus := halfLoss32(n, input, target)
+
+	*w = original
+
+	return (plus - minus) / (2 * eps)
+}
+
+// halfLoss32 is -0.5 times the squared-error loss. BackPropagate's
+// accumulated gradient, DF(sum)*(target-activation)*input, is this
+// quantity's true gradient rather than the squared error's: differentiate
+// L=-0.5*e^2 and the DF(sum)*e factor falls out with the same sign and
+// scale BackPropagate uses. numericGradient32 must finite-difference the
+// same loss CheckGradient's analytic side actually corresponds to, or the
+// comparison is off by a factor of -2 regardless of whether backprop is
+// correct.
+func halfLoss32(n *Neural32, input, target []float32) float32 {
+	context := n.NewContext()
+	context.SetInput(input)
+	context.Infer()
+
+	var e float32
+	for i, t := range target {
+		f := t - context.GetOutput()[i]
+		e += f * f
+	}
+	return -0.5 * e
+}
+
+func absFloat32(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
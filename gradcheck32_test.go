@@ -0,0 +1,59 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCheckGradientActivations(t *testing.T) {
+	activations := []Activation32{Sigmoid32, Tanh32, ReLU32, LeakyReLU32, ELU32, SiLU32}
+
+	for _, act := range activations {
+		t.Run(act.String(), func(t *testing.T) {
+			rand.Seed(0)
+
+			n := NewNeural32(func(neural *Neural32) {
+				neural.Init(WeightInitializer32FanIn, 2, 4, 1)
+				for l := range neural.Functions {
+					neural.SetActivation(l, act)
+				}
+			})
+
+			maxRelErr, _ := n.CheckGradient([]float32{0, 1}, []float32{1}, 1e-2)
+			if maxRelErr > 5e-2 {
+				t.Fatalf("%s: max relative error %v exceeds tolerance", act, maxRelErr)
+			}
+		})
+	}
+}
+
+// TestCheckGradientDeepNetwork covers a network with two hidden layers,
+// where a middle layer's bias index is a real operand of the backward pass
+// one layer further back (not just a dead row of its own weight matrix, as
+// in the single-hidden-layer case above): BackPropagate must still exclude
+// it from every delta and gradient it computes.
+func TestCheckGradientDeepNetwork(t *testing.T) {
+	activations := []Activation32{Sigmoid32, Tanh32, ReLU32, LeakyReLU32, ELU32, SiLU32}
+
+	for _, act := range activations {
+		t.Run(act.String(), func(t *testing.T) {
+			rand.Seed(0)
+
+			n := NewNeural32(func(neural *Neural32) {
+				neural.Init(WeightInitializer32FanIn, 2, 3, 3, 1)
+				for l := range neural.Functions {
+					neural.SetActivation(l, act)
+				}
+			})
+
+			maxRelErr, _ := n.CheckGradient([]float32{0, 1}, []float32{1}, 1e-2)
+			if maxRelErr > 5e-2 {
+				t.Fatalf("%s: max relative error %v exceeds tolerance", act, maxRelErr)
+			}
+		})
+	}
+}
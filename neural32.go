@@ -23,8 +23,12 @@ type FunctionPair32 struct {
 type Neural32 struct {
 	Layers    []int
 	Weights   [][][]float32
-	Changes   [][][]float32
 	Functions []FunctionPair32
+
+	activations []string
+	softmax     bool
+	regression  bool
+	dropout     float32
 }
 
 // WeightInitializer32 is a function that initializes the neural network weights
@@ -69,26 +73,18 @@ func (n *Neural32) Init(initializer WeightInitializer32, layers ...int) {
 		n.Weights[l] = weights
 	}
 
-	n.Changes = make([][][]float32, depth)
-	for l := range layers[:depth] {
-		n.Changes[l] = matrix32(layers[l], layers[l+1])
-	}
-
 	n.Functions = make([]FunctionPair32, depth)
+	n.activations = make([]string, depth)
 	for f := range n.Functions {
-		n.Functions[f] = FunctionPair32{
-			F:  sigmoid32,
-			T:  identity,
-			DF: dsigmoid32,
-		}
+		n.Functions[f] = Sigmoid32.pair
+		n.activations[f] = Sigmoid32.name
 	}
 }
 
 // UseTanh use tanh for the activation function
 func (n *Neural32) UseTanh() {
 	for f := range n.Functions {
-		n.Functions[f].F = tanh32
-		n.Functions[f].DF = dtanh32
+		n.SetActivation(f, Tanh32)
 	}
 }
 
@@ -96,24 +92,19 @@ func (n *Neural32) UseTanh() {
 // that regression is performed
 func (n *Neural32) EnableRegression() {
 	output := len(n.Functions) - 1
-	n.Functions[output].F = identity
-	n.Functions[output].DF = one
+	n.SetActivation(output, Identity32)
+	n.regression = true
 }
 
-// EnableDropout enables dropout based regularization
+// EnableDropout enables dropout based regularization on every hidden
+// layer. InferWithT draws and applies each neuron's mask, recording it
+// into Context32.DropoutScale so BackPropagate can zero or scale its
+// delta by the same factor; the mask can't be smuggled through
+// FunctionPair32.T itself since T is shared across every Context32 a
+// network's concurrent TrainParallel workers use.
 // See: http://iamtrask.github.io/2015/07/28/dropout/
 func (n *Neural32) EnableDropout(probability float32) {
-	depth := len(n.Layers) - 1
-	for i := range n.Functions[:depth-1] {
-		n.Functions[i].T = func(x float32) float32 {
-			if rand.Float32() > 1-probability {
-				x = 0
-			} else {
-				x *= 1 / (1 - probability)
-			}
-			return x
-		}
-	}
+	n.dropout = probability
 }
 
 // NewNeural32 creates a neural network with the given configuration
@@ -127,6 +118,42 @@ func NewNeural32(config func(neural *Neural32)) *Neural32 {
 type Context32 struct {
 	*Neural32
 	Activations [][]float32
+
+	// Sums holds each neuron's pre-activation weighted sum, as computed by
+	// Infer/InferWithT. BackPropagate passes it to FunctionPair32.DF so
+	// that activations such as ReLU, whose derivative is not a function of
+	// the post-activation value alone, can be differentiated correctly.
+	// Sums[0] (the input layer) is unused. Neither Infer nor InferWithT
+	// ever writes a layer's last (bias) index, since the bias neuron's
+	// fixed activation does not depend on a weighted sum; BackPropagate
+	// must not read Sums there.
+	Sums [][]float32
+
+	// DropoutScale holds the per-neuron multiplier InferWithT applied this
+	// pass: 0 for a neuron dropped by EnableDropout, 1/(1-probability) for
+	// one kept, or 1 everywhere dropout does not apply (including every
+	// output-layer neuron, which dropout never touches). BackPropagate
+	// multiplies a neuron's delta by it, the same chain-rule factor
+	// InferWithT applied going forward, so gradient does not flow through
+	// a neuron that was zeroed for this pattern.
+	DropoutScale [][]float32
+
+	// Grads accumulates the per-weight gradient of the current mini-batch,
+	// shaped like Weights ([l][j][i]). BackPropagate adds to it; Train
+	// resets it and hands it to the Optimizer32 once a batch is complete.
+	Grads [][][]float32
+
+	// TrackInputGrad, when set before BackPropagate, asks it to also
+	// populate InputGrad. Ordinary MLP training leaves this false to skip
+	// the extra work; callers that need to propagate a gradient into
+	// something upstream of the input layer (e.g. ConvNet32's pooled
+	// convolution output) set it.
+	TrackInputGrad bool
+	// InputGrad is the negated gradient of half the squared-error loss
+	// (the descent direction, the same sign convention as Grads) with
+	// respect to the input layer's activations (excluding the bias neuron), valid after a
+	// BackPropagate call made with TrackInputGrad set.
+	InputGrad []float32
 }
 
 // SetInput sets the input to the neural network
@@ -144,13 +171,35 @@ func (n *Neural32) NewContext() *Context32 {
 	layers, depth := n.Layers, len(n.Layers)
 
 	activations := make([][]float32, depth)
+	sums := make([][]float32, depth)
+	dropoutScale := make([][]float32, depth)
 	for i, width := range layers {
 		activations[i] = vector32(width, 1.0)
+		sums[i] = vector32(width, 0)
+		dropoutScale[i] = vector32(width, 1.0)
+	}
+
+	grads := make([][][]float32, depth-1)
+	for l := range grads {
+		grads[l] = matrix32(layers[l+1], layers[l])
 	}
 
 	return &Context32{
-		Neural32:    n,
-		Activations: activations,
+		Neural32:     n,
+		Activations:  activations,
+		Sums:         sums,
+		DropoutScale: dropoutScale,
+		Grads:        grads,
+	}
+}
+
+func (c *Context32) resetGrads() {
+	for l := range c.Grads {
+		for j := range c.Grads[l] {
+			for i := range c.Grads[l][j] {
+				c.Grads[l][j][i] = 0
+			}
+		}
 	}
 }
 
@@ -163,20 +212,27 @@ func (c *Context32) Infer() {
 			activations, weights := c.Activations[i], c.Weights[i]
 			for j := range weights[:len(weights)-1] {
 				sum := dot32(activations, weights[j])
+				c.Sums[i+1][j] = sum
 				c.Activations[i+1][j] = c.Functions[i].F(sum)
 			}
 		}
 	}
 
 	i := depth - 1
+	if c.softmax {
+		c.inferSoftmax(i)
+		return
+	}
 	activations, weights := c.Activations[i], c.Weights[i]
 	for j := range weights[:len(weights)] {
 		sum := dot32(activations, weights[j])
+		c.Sums[i+1][j] = sum
 		c.Activations[i+1][j] = c.Functions[i].F(sum)
 	}
 }
 
-// InferWithT runs inference using a transform in between layers
+// InferWithT runs inference using a transform in between layers, drawing
+// and applying each hidden neuron's EnableDropout mask along the way.
 func (c *Context32) InferWithT() {
 	depth := len(c.Layers) - 1
 
@@ -185,21 +241,68 @@ func (c *Context32) InferWithT() {
 			activations, weights := c.Activations[i], c.Weights[i]
 			for j := range weights[:len(weights)-1] {
 				sum := dot32(activations, weights[j])
-				c.Activations[i+1][j] = c.Functions[i].T(c.Functions[i].F(sum))
+				c.Sums[i+1][j] = sum
+				scale := c.dropoutScale()
+				c.DropoutScale[i+1][j] = scale
+				c.Activations[i+1][j] = scale * c.Functions[i].T(c.Functions[i].F(sum))
 			}
 		}
 	}
 
 	i := depth - 1
+	if c.softmax {
+		c.inferSoftmax(i)
+		return
+	}
 	activations, weights := c.Activations[i], c.Weights[i]
 	for j := range weights[:len(weights)] {
 		sum := dot32(activations, weights[j])
+		c.Sums[i+1][j] = sum
 		c.Activations[i+1][j] = c.Functions[i].T(c.Functions[i].F(sum))
 	}
 }
 
-// BackPropagate run the backpropagation algorithm
-func (c *Context32) BackPropagate(targets []float32, lRate, mFactor float32) float32 {
+// inferSoftmax computes the weighted sums for output layer i and normalizes
+// them across the whole layer, since softmax (unlike the other
+// activations) cannot be expressed as a per-neuron Function32.
+func (c *Context32) inferSoftmax(i int) {
+	activations, weights := c.Activations[i], c.Weights[i]
+	sums := c.Sums[i+1]
+	for j := range weights {
+		sums[j] = dot32(activations, weights[j])
+	}
+	softmax32(sums, c.Activations[i+1])
+}
+
+// dropoutScale draws one neuron's inverted-dropout multiplier: 0 if it is
+// dropped for this pattern, 1/(1-probability) if kept, or 1 when the
+// network has no dropout enabled.
+func (c *Context32) dropoutScale() float32 {
+	if c.dropout <= 0 {
+		return 1
+	}
+	if rand.Float32() > 1-c.dropout {
+		return 0
+	}
+	return 1 / (1 - c.dropout)
+}
+
+// BackPropagate runs the backpropagation algorithm, accumulating this
+// pattern's contribution to the current mini-batch's gradient into
+// c.Grads, and returns the squared-error loss for the pattern (the
+// cross-entropy loss when the output layer is Softmax32). It does not
+// touch Weights; Train applies the accumulated gradient through an
+// Optimizer32 once a mini-batch is complete.
+//
+// Every layer but the last carries a synthetic bias neuron as its last
+// index (Init appends it); neither Infer nor InferWithT ever computes a
+// Sums entry for it, since the bias's fixed activation does not depend on
+// a weighted sum. BackPropagate must therefore never compute or use a
+// delta at a bias index: deltas is zero-filled and only ever written at
+// real neuron indices, so a bias slot reads back as the harmless 0 it
+// started as, rather than FunctionPair32.DF evaluated at the bias's
+// unwritten (always zero) Sums entry.
+func (c *Context32) BackPropagate(targets []float32) float32 {
 	depth, layers := len(c.Layers), c.Layers
 
 	deltas := make([][]float32, depth-1)
@@ -209,39 +312,54 @@ func (c *Context32) BackPropagate(targets []float32, lRate, mFactor float32) flo
 
 	l := depth - 2
 	for i := 0; i < layers[l+1]; i++ {
-		activation := c.Activations[l+1][i]
-		e := targets[i] - activation
-		deltas[l][i] = c.Functions[l].DF(activation) * e
+		e := targets[i] - c.Activations[l+1][i]
+		deltas[l][i] = c.DropoutScale[l+1][i] * c.Functions[l].DF(c.Sums[l+1][i]) * e
 	}
 	l--
 
 	for l >= 0 {
-		for i := 0; i < layers[l+1]; i++ {
+		real := layers[l+1] - 1
+		for i := 0; i < real; i++ {
 			var e float32
 
 			for j := 0; j < layers[l+2]; j++ {
 				e += deltas[l+1][j] * c.Weights[l+1][j][i]
 			}
 
-			deltas[l][i] = c.Functions[l].DF(c.Activations[l+1][i]) * e
+			deltas[l][i] = c.DropoutScale[l+1][i] * c.Functions[l].DF(c.Sums[l+1][i]) * e
 		}
 		l--
 	}
 
 	for l := 0; l < depth-1; l++ {
-		change := make([]float32, layers[l+1])
-		for i := 0; i < layers[l]; i++ {
-			copy(change, deltas[l])
-			scal32(c.Activations[l][i], change)
-			scal32(mFactor, c.Changes[l][i])
-			axpy32(lRate, change, c.Changes[l][i])
-			for j := 0; j < layers[l+1]; j++ {
-				c.Weights[l][j][i] = c.Weights[l][j][i] + c.Changes[l][i][j]
+		grads, activations := c.Grads[l], c.Activations[l]
+		real := layers[l+1]
+		if l < depth-2 {
+			real--
+		}
+		for j := 0; j < real; j++ {
+			d := deltas[l][j]
+			for i := 0; i < layers[l]; i++ {
+				grads[j][i] += d * activations[i]
 			}
-			copy(c.Changes[l][i], change)
 		}
 	}
 
+	if c.TrackInputGrad {
+		c.InputGrad = make([]float32, layers[0]-1)
+		for i := range c.InputGrad {
+			var g float32
+			for j := 0; j < layers[1]; j++ {
+				g += deltas[0][j] * c.Weights[0][j][i]
+			}
+			c.InputGrad[i] = g
+		}
+	}
+
+	if c.softmax {
+		return CrossEntropyLoss32(c.Activations[depth-1], targets)
+	}
+
 	var e float32
 	for i := 0; i < len(targets); i++ {
 		f := targets[i] - c.Activations[depth-1][i]
@@ -251,8 +369,43 @@ func (c *Context32) BackPropagate(targets []float32, lRate, mFactor float32) flo
 	return e
 }
 
-// Train trains a neural network using data from source
-func (n *Neural32) Train(source func(iteration int) [][][]float32, iterations int, lRate, mFactor float32) []float32 {
+// apply divides the accumulated mini-batch gradient by batchSize, subtracts
+// the regularizer's penalty, and runs the optimizer's update over every weight.
+func (c *Context32) apply(optimizer Optimizer32, lRate float32, batchSize int, reg Regularizer32) {
+	applyGradient32(c.Weights, c.Gradient(), optimizer, lRate, batchSize, reg)
+	c.resetGrads()
+}
+
+// TrainConfig bundles the hyperparameters used by Train. Optimizer defaults
+// to MomentumOptimizer32 (factor 0.4) and BatchSize defaults to 1 (pure
+// online SGD, the network's original behavior) when left zero, reproducing
+// Train's pre-Optimizer32 behavior for networks without dropout. With
+// EnableDropout, BackPropagate now scales each hidden delta by
+// DropoutScale and evaluates DF at the pre-activation sum, so such
+// networks train differently than before.
+type TrainConfig struct {
+	BatchSize   int
+	LRate       float32
+	Optimizer   Optimizer32
+	Regularizer Regularizer32
+	Shuffle     bool
+}
+
+// Train trains a neural network using data from source, accumulating
+// gradients over mini-batches of config.BatchSize patterns and applying
+// them with config.Optimizer.
+func (n *Neural32) Train(source func(iteration int) [][][]float32, iterations int, config TrainConfig) []float32 {
+	batchSize := config.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	optimizer := config.Optimizer
+	if optimizer == nil {
+		optimizer = &MomentumOptimizer32{Factor: 0.4}
+	}
+	optimizer.Init(n.Layers)
+
 	context, errors := n.NewContext(), make([]float32, iterations)
 
 	for i := 0; i < iterations; i++ {
@@ -262,12 +415,26 @@ func (n *Neural32) Train(source func(iteration int) [][][]float32, iterations in
 		)
 
 		patterns := source(i)
+		if config.Shuffle {
+			rand.Shuffle(len(patterns), func(a, b int) {
+				patterns[a], patterns[b] = patterns[b], patterns[a]
+			})
+		}
+
+		for start := 0; start < len(patterns); start += batchSize {
+			end := start + batchSize
+			if end > len(patterns) {
+				end = len(patterns)
+			}
 
-		for _, p := range patterns {
-			context.SetInput(p[0])
-			context.InferWithT()
-			e += context.BackPropagate(p[1], lRate, mFactor)
-			n += len(p[1])
+			for _, p := range patterns[start:end] {
+				context.SetInput(p[0])
+				context.InferWithT()
+				e += context.BackPropagate(p[1])
+				n += len(p[1])
+			}
+
+			context.apply(optimizer, config.LRate, end-start, config.Regularizer)
 		}
 
 		errors[i] = e / float32(n)
@@ -276,6 +443,32 @@ func (n *Neural32) Train(source func(iteration int) [][][]float32, iterations in
 	return errors
 }
 
+// Clone returns a deep copy of n, including its trained weights, topology,
+// and activation configuration, independent of n's backing arrays. It is
+// the basis for algorithms such as TrainGA that evolve a population of
+// networks sharing a common topology.
+func (n *Neural32) Clone() *Neural32 {
+	clone := &Neural32{
+		Layers:      append([]int(nil), n.Layers...),
+		Functions:   append([]FunctionPair32(nil), n.Functions...),
+		activations: append([]string(nil), n.activations...),
+		softmax:     n.softmax,
+		regression:  n.regression,
+		dropout:     n.dropout,
+	}
+
+	clone.Weights = make([][][]float32, len(n.Weights))
+	for l, weights := range n.Weights {
+		cloned := matrix32(len(weights), len(weights[0]))
+		for j, row := range weights {
+			copy(cloned[j], row)
+		}
+		clone.Weights[l] = cloned
+	}
+
+	return clone
+}
+
 func (n *Neural32) test(patterns [][][]float32) {
 	context := n.NewContext()
 	for _, p := range patterns {
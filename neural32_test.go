@@ -25,7 +25,10 @@ func ExampleSimpleNeural32() {
 	}
 	n := NewNeural32(config)
 
-	n.Train(source, 1000, 0.6, 0.4)
+	n.Train(source, 1000, TrainConfig{
+		LRate:     0.6,
+		Optimizer: &MomentumOptimizer32{Factor: 0.4},
+	})
 
 	n.test(patterns)
 
@@ -45,7 +48,10 @@ func ExampleRegressionNeural32() {
 	}
 	n := NewNeural32(config)
 
-	n.Train(source, 1000, 0.6, 0.4)
+	n.Train(source, 1000, TrainConfig{
+		LRate:     0.6,
+		Optimizer: &MomentumOptimizer32{Factor: 0.4},
+	})
 
 	n.test(patterns)
 
@@ -74,7 +80,10 @@ func ExampleDropoutNeural32() {
 		}
 		return randomized
 	}
-	n.Train(src, 10000, 0.6, 0.4)
+	n.Train(src, 10000, TrainConfig{
+		LRate:     0.6,
+		Optimizer: &MomentumOptimizer32{Factor: 0.4},
+	})
 
 	n.test(patterns)
 
@@ -93,7 +102,10 @@ func ExampleDeepNeural32() {
 	}
 	n := NewNeural32(config)
 
-	n.Train(source, 10000, 0.6, 0.4)
+	n.Train(source, 10000, TrainConfig{
+		LRate:     0.6,
+		Optimizer: &MomentumOptimizer32{Factor: 0.4},
+	})
 
 	n.test(patterns)
 
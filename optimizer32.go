@@ -0,0 +1,213 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import "math"
+
+// Optimizer32 turns a mini-batch's accumulated per-weight gradient into the
+// delta that Train adds to that weight. Implementations own whatever
+// per-weight state they need (momentum, moment estimates, ...), allocated
+// by Init to match a network's layer sizes.
+type Optimizer32 interface {
+	// Init (re)allocates the optimizer's per-weight state for a network
+	// whose layer sizes (post bias-augmentation) are layers, matching
+	// Neural32.Layers.
+	Init(layers []int)
+
+	// BeginBatch is called once before a mini-batch's gradients are
+	// applied, letting optimizers such as Adam advance a step counter used
+	// for bias correction.
+	BeginBatch()
+
+	// Step returns the delta to add to weight Weights[l][j][i] given its
+	// accumulated, batch-averaged gradient g and the configured learning
+	// rate.
+	Step(l, j, i int, g, lRate float32) float32
+}
+
+func optimizerState32(layers []int) [][][]float32 {
+	depth := len(layers) - 1
+	state := make([][][]float32, depth)
+	for l := 0; l < depth; l++ {
+		state[l] = matrix32(layers[l+1], layers[l])
+	}
+	return state
+}
+
+// MomentumOptimizer32 is the network's original momentum update: the step
+// added to a weight is Factor times the *previous* batch's raw gradient
+// plus lRate times the current one, and only the raw gradient (not the
+// blended step) is kept for next time. This is a one-batch lag, not a
+// recursively-decaying running average, and is the default so that
+// existing callers of Train see unchanged behavior. Factor 0 reduces to
+// plain SGD.
+type MomentumOptimizer32 struct {
+	Factor float32
+
+	prevGrad [][][]float32
+}
+
+// Init implements Optimizer32.
+func (o *MomentumOptimizer32) Init(layers []int) {
+	o.prevGrad = optimizerState32(layers)
+}
+
+// BeginBatch implements Optimizer32.
+func (o *MomentumOptimizer32) BeginBatch() {}
+
+// Step implements Optimizer32.
+func (o *MomentumOptimizer32) Step(l, j, i int, g, lRate float32) float32 {
+	v := o.Factor*o.prevGrad[l][j][i] + lRate*g
+	o.prevGrad[l][j][i] = g
+	return v
+}
+
+// NesterovOptimizer32 is Nesterov accelerated momentum: it applies the
+// velocity computed one step ahead of the classical momentum update,
+// which damps oscillation around narrow minima.
+type NesterovOptimizer32 struct {
+	Factor float32
+
+	velocity [][][]float32
+}
+
+// Init implements Optimizer32.
+func (o *NesterovOptimizer32) Init(layers []int) {
+	o.velocity = optimizerState32(layers)
+}
+
+// BeginBatch implements Optimizer32.
+func (o *NesterovOptimizer32) BeginBatch() {}
+
+// Step implements Optimizer32.
+func (o *NesterovOptimizer32) Step(l, j, i int, g, lRate float32) float32 {
+	prev := o.velocity[l][j][i]
+	v := o.Factor*prev + lRate*g
+	o.velocity[l][j][i] = v
+	return o.Factor*v + lRate*g
+}
+
+// RMSPropOptimizer32 scales the learning rate of each weight by a running
+// average of the squared gradient, so weights with noisy, large gradients
+// take smaller steps.
+type RMSPropOptimizer32 struct {
+	Decay   float32 // defaults to 0.9 when zero
+	Epsilon float32 // defaults to 1e-8 when zero
+
+	v [][][]float32
+}
+
+// Init implements Optimizer32.
+func (o *RMSPropOptimizer32) Init(layers []int) {
+	if o.Decay == 0 {
+		o.Decay = 0.9
+	}
+	if o.Epsilon == 0 {
+		o.Epsilon = 1e-8
+	}
+	o.v = optimizerState32(layers)
+}
+
+// BeginBatch implements Optimizer32.
+func (o *RMSPropOptimizer32) BeginBatch() {}
+
+// Step implements Optimizer32.
+func (o *RMSPropOptimizer32) Step(l, j, i int, g, lRate float32) float32 {
+	v := o.Decay*o.v[l][j][i] + (1-o.Decay)*g*g
+	o.v[l][j][i] = v
+	return lRate * g / (float32(math.Sqrt(float64(v))) + o.Epsilon)
+}
+
+// AdamOptimizer32 combines momentum (the first moment m) with a per-weight
+// RMSProp-style learning rate (the second moment v), both bias-corrected
+// for their warm start at zero.
+//
+//	m = beta1*m + (1-beta1)*g
+//	v = beta2*v + (1-beta2)*g^2
+//	update = lRate * m_hat / (sqrt(v_hat) + epsilon)
+type AdamOptimizer32 struct {
+	Beta1   float32 // defaults to 0.9 when zero
+	Beta2   float32 // defaults to 0.999 when zero
+	Epsilon float32 // defaults to 1e-8 when zero
+
+	t    int
+	m, v [][][]float32
+}
+
+// Init implements Optimizer32.
+func (o *AdamOptimizer32) Init(layers []int) {
+	if o.Beta1 == 0 {
+		o.Beta1 = 0.9
+	}
+	if o.Beta2 == 0 {
+		o.Beta2 = 0.999
+	}
+	if o.Epsilon == 0 {
+		o.Epsilon = 1e-8
+	}
+	o.t = 0
+	o.m = optimizerState32(layers)
+	o.v = optimizerState32(layers)
+}
+
+// BeginBatch implements Optimizer32.
+func (o *AdamOptimizer32) BeginBatch() {
+	o.t++
+}
+
+// Step implements Optimizer32.
+func (o *AdamOptimizer32) Step(l, j, i int, g, lRate float32) float32 {
+	m := o.Beta1*o.m[l][j][i] + (1-o.Beta1)*g
+	v := o.Beta2*o.v[l][j][i] + (1-o.Beta2)*g*g
+	o.m[l][j][i], o.v[l][j][i] = m, v
+
+	t := float64(o.t)
+	mHat := m / (1 - float32(math.Pow(float64(o.Beta1), t)))
+	vHat := v / (1 - float32(math.Pow(float64(o.Beta2), t)))
+
+	return lRate * mHat / (float32(math.Sqrt(float64(vHat))) + o.Epsilon)
+}
+
+// RegularizationKind32 selects the penalty Regularizer32 adds to a
+// gradient before the optimizer step.
+type RegularizationKind32 uint8
+
+const (
+	// RegularizationNone32 applies no penalty.
+	RegularizationNone32 RegularizationKind32 = iota
+	// RegularizationL1_32 penalizes by lambda*sign(w), encouraging sparse
+	// weights.
+	RegularizationL1_32
+	// RegularizationL2_32 penalizes by lambda*w, encouraging small
+	// weights.
+	RegularizationL2_32
+)
+
+// Regularizer32 is an L1 or L2 weight penalty applied before the optimizer
+// step. The zero value applies no penalty.
+type Regularizer32 struct {
+	Kind   RegularizationKind32
+	Lambda float32
+}
+
+// penalty returns the regularization term for a weight with value w, which
+// apply subtracts from the weight's gradient.
+func (r Regularizer32) penalty(w float32) float32 {
+	switch r.Kind {
+	case RegularizationL1_32:
+		switch {
+		case w > 0:
+			return r.Lambda
+		case w < 0:
+			return -r.Lambda
+		default:
+			return 0
+		}
+	case RegularizationL2_32:
+		return r.Lambda * w
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import "sync"
+
+// Gradient32 is a dense snapshot of an accumulated weight gradient, shaped
+// like Neural32.Weights ([l][j][i]). TrainParallel has each worker return
+// one so that a Reducer32 can sum them before a single weight update is
+// applied per iteration.
+type Gradient32 struct {
+	Values [][][]float32
+}
+
+// NewGradient32 allocates a zeroed Gradient32 for a network whose layer
+// sizes (post bias-augmentation) are layers, matching Neural32.Layers.
+func NewGradient32(layers []int) *Gradient32 {
+	return &Gradient32{Values: optimizerState32(layers)}
+}
+
+// Gradient wraps c's in-progress mini-batch accumulation as a Gradient32,
+// without copying, so a worker goroutine can hand its gradient to a
+// Reducer32 once it finishes its shard.
+func (c *Context32) Gradient() *Gradient32 {
+	return &Gradient32{Values: c.Grads}
+}
+
+// Add sums other into g with axpy32.
+func (g *Gradient32) Add(other *Gradient32) {
+	for l := range g.Values {
+		for j := range g.Values[l] {
+			axpy32(1, other.Values[l][j], g.Values[l][j])
+		}
+	}
+}
+
+// Reset zeros every entry, letting a Gradient32 be reused across
+// iterations.
+func (g *Gradient32) Reset() {
+	for l := range g.Values {
+		for j := range g.Values[l] {
+			for i := range g.Values[l][j] {
+				g.Values[l][j][i] = 0
+			}
+		}
+	}
+}
+
+// Reducer32 sums the Gradient32s produced by TrainParallel's workers into a
+// single Gradient32 ready for one weight update.
+type Reducer32 struct {
+	total *Gradient32
+}
+
+// NewReducer32 allocates a Reducer32 for a network whose layer sizes
+// (post bias-augmentation) are layers.
+func NewReducer32(layers []int) *Reducer32 {
+	return &Reducer32{total: NewGradient32(layers)}
+}
+
+// Reduce sums grads and returns the result. The returned Gradient32 is
+// reused across calls; callers must finish using it before the next call.
+func (r *Reducer32) Reduce(grads []*Gradient32) *Gradient32 {
+	r.total.Reset()
+	for _, g := range grads {
+		r.total.Add(g)
+	}
+	return r.total
+}
+
+// applyGradient32 divides grad by scaleBy, subtracts the regularizer's
+// penalty, and runs the optimizer's update over every weight in weights. It
+// underlies both Context32.apply (a single mini-batch's local gradient)
+// and TrainParallel (a Reducer32's summed gradient).
+func applyGradient32(weights [][][]float32, grad *Gradient32, optimizer Optimizer32, lRate float32, scaleBy int, reg Regularizer32) {
+	optimizer.BeginBatch()
+
+	scale := 1 / float32(scaleBy)
+	for l := range weights {
+		ws, gs := weights[l], grad.Values[l]
+		for j := range ws {
+			for i := range ws[j] {
+				g := gs[j][i]*scale - reg.penalty(ws[j][i])
+				ws[j][i] += optimizer.Step(l, j, i, g, lRate)
+			}
+		}
+	}
+}
+
+// TrainParallel is the data-parallel analogue of Train: each iteration it
+// shards the pattern batch from source across nWorkers goroutines, each
+// with its own Context32 (separate activation and gradient buffers) over
+// n's shared, read-only-during-the-shard Weights, then sums the workers'
+// gradients with a Reducer32 and applies a single MomentumOptimizer32
+// update (factor mFactor) before the next iteration.
+func (n *Neural32) TrainParallel(source func(iteration int) [][][]float32, iterations, nWorkers int, lRate, mFactor float32) []float32 {
+	if nWorkers < 1 {
+		nWorkers = 1
+	}
+
+	contexts := make([]*Context32, nWorkers)
+	for w := range contexts {
+		contexts[w] = n.NewContext()
+	}
+
+	optimizer := &MomentumOptimizer32{Factor: mFactor}
+	optimizer.Init(n.Layers)
+	reducer := NewReducer32(n.Layers)
+
+	errors := make([]float32, iterations)
+
+	for i := 0; i < iterations; i++ {
+		patterns := source(i)
+
+		shards := make([][][][]float32, nWorkers)
+		for idx, p := range patterns {
+			w := idx % nWorkers
+			shards[w] = append(shards[w], p)
+		}
+
+		losses, counts := make([]float32, nWorkers), make([]int, nWorkers)
+		grads := make([]*Gradient32, nWorkers)
+
+		var wg sync.WaitGroup
+		for w := 0; w < nWorkers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+
+				context := contexts[w]
+				var e float32
+				for _, p := range shards[w] {
+					context.SetInput(p[0])
+					context.InferWithT()
+					e += context.BackPropagate(p[1])
+				}
+				losses[w], counts[w] = e, len(shards[w])
+				grads[w] = context.Gradient()
+			}(w)
+		}
+		wg.Wait()
+
+		total := reducer.Reduce(grads)
+
+		var e float32
+		var count int
+		for w := range contexts {
+			e += losses[w]
+			count += counts[w]
+			contexts[w].resetGrads()
+		}
+
+		if count > 0 {
+			applyGradient32(n.Weights, total, optimizer, lRate, count, Regularizer32{})
+		}
+
+		var targetCount int
+		for _, p := range patterns {
+			targetCount += len(p[1])
+		}
+		errors[i] = e / float32(targetCount)
+	}
+
+	return errors
+}
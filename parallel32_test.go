@@ -0,0 +1,43 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTrainParallel(t *testing.T) {
+	rand.Seed(0)
+
+	config := func(neural *Neural32) {
+		neural.Init(WeightInitializer32FanIn, 2, 2, 2, 1)
+	}
+	n := NewNeural32(config)
+
+	errors := n.TrainParallel(source, 1000, 4, 0.6, 0.4)
+
+	if errors[len(errors)-1] >= errors[0] {
+		t.Fatalf("error did not decrease: first %v, last %v", errors[0], errors[len(errors)-1])
+	}
+}
+
+func benchmarkTrainParallel(b *testing.B, nWorkers int) {
+	rand.Seed(0)
+
+	config := func(neural *Neural32) {
+		neural.Init(WeightInitializer32FanIn, 2, 8, 1)
+	}
+
+	for i := 0; i < b.N; i++ {
+		n := NewNeural32(config)
+		n.TrainParallel(source, 100, nWorkers, 0.6, 0.4)
+	}
+}
+
+func BenchmarkTrainParallel1(b *testing.B) { benchmarkTrainParallel(b, 1) }
+func BenchmarkTrainParallel2(b *testing.B) { benchmarkTrainParallel(b, 2) }
+func BenchmarkTrainParallel4(b *testing.B) { benchmarkTrainParallel(b, 4) }
+func BenchmarkTrainParallel8(b *testing.B) { benchmarkTrainParallel(b, 8) }
@@ -0,0 +1,179 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Format32 selects the on-disk representation used by Save and SaveFile.
+type Format32 uint8
+
+const (
+	// FormatGob32 is a compact binary encoding.
+	FormatGob32 Format32 = iota
+	// FormatJSON32 is a portable, human readable encoding.
+	FormatJSON32
+)
+
+const (
+	magic32       = "NRL32"
+	formatVersion = 2
+	dtypeFloat32  = 1
+)
+
+// ErrBadMagic32 is returned by Load when the stream does not start with the
+// Neural32 magic bytes.
+var ErrBadMagic32 = errors.New("neural: bad magic bytes")
+
+// ErrUnsupportedVersion32 is returned by Load when the stream was written
+// with any format version other than this build's, older or newer.
+var ErrUnsupportedVersion32 = errors.New("neural: unsupported format version")
+
+// ErrUnsupportedDType32 is returned by Load when the stream holds weights of
+// a dtype this build of Neural32 cannot read.
+var ErrUnsupportedDType32 = errors.New("neural: unsupported float dtype")
+
+// header32 precedes the encoded model body so that a Load on an incompatible
+// or corrupt stream fails cleanly instead of producing a garbage network.
+type header32 struct {
+	Magic   [5]byte
+	Version uint32
+	DType   uint8
+	Format  Format32
+}
+
+// state32 is the serializable snapshot of a Neural32.
+type state32 struct {
+	Layers      []int
+	Weights     [][][]float32
+	Activations []string
+	Softmax     bool
+	Regression  bool
+	Dropout     float32
+}
+
+func (n *Neural32) state() state32 {
+	return state32{
+		Layers:      n.Layers,
+		Weights:     n.Weights,
+		Activations: n.activations,
+		Softmax:     n.softmax,
+		Regression:  n.regression,
+		Dropout:     n.dropout,
+	}
+}
+
+func (n *Neural32) restore(s state32) error {
+	n.Layers = s.Layers
+	n.Weights = s.Weights
+	n.activations = s.Activations
+	n.regression = s.Regression
+	n.dropout = s.Dropout
+
+	n.Functions = make([]FunctionPair32, len(n.Layers)-1)
+	for f, name := range n.activations {
+		if name == Softmax32.name {
+			continue // restored via SetOutputActivation below
+		}
+		act, ok := activationRegistry32[name]
+		if !ok {
+			return fmt.Errorf("neural: unknown activation %q", name)
+		}
+		n.Functions[f] = act.pair
+	}
+	if s.Softmax {
+		n.SetOutputActivation(Softmax32)
+	}
+	if n.dropout > 0 {
+		n.EnableDropout(n.dropout)
+	}
+	return nil
+}
+
+// Save writes the trained weights, topology, and training options of n to w
+// using the given format, preceded by a header that lets Load reject
+// incompatible streams before they are decoded.
+func (n *Neural32) Save(w io.Writer, format Format32) error {
+	var h header32
+	copy(h.Magic[:], magic32)
+	h.Version = formatVersion
+	h.DType = dtypeFloat32
+	h.Format = format
+
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		return err
+	}
+
+	s := n.state()
+	switch format {
+	case FormatJSON32:
+		return json.NewEncoder(w).Encode(s)
+	default:
+		return gob.NewEncoder(w).Encode(s)
+	}
+}
+
+// Load reads a model previously written by Save, detecting the format from
+// the header.
+func (n *Neural32) Load(r io.Reader) error {
+	var h header32
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return err
+	}
+	if string(h.Magic[:]) != magic32 {
+		return ErrBadMagic32
+	}
+	if h.Version != formatVersion {
+		return fmt.Errorf("%w: got %d, want %d", ErrUnsupportedVersion32, h.Version, formatVersion)
+	}
+	if h.DType != dtypeFloat32 {
+		return ErrUnsupportedDType32
+	}
+
+	var s state32
+	var err error
+	switch h.Format {
+	case FormatJSON32:
+		err = json.NewDecoder(r).Decode(&s)
+	default:
+		err = gob.NewDecoder(r).Decode(&s)
+	}
+	if err != nil {
+		return err
+	}
+
+	return n.restore(s)
+}
+
+// SaveFile is a convenience wrapper around Save that writes to the named
+// file, creating or truncating it as needed.
+func (n *Neural32) SaveFile(path string, format Format32) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return n.Save(f, format)
+}
+
+// LoadFile is a convenience wrapper around Load that reads from the named
+// file.
+func (n *Neural32) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return n.Load(f)
+}
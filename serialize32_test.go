@@ -0,0 +1,133 @@
+// Copyright 2016 The Neural Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package neural
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	configs := map[string]func(neural *Neural32){
+		"sigmoid": func(neural *Neural32) {
+			neural.Init(WeightInitializer32FanIn, 2, 4, 1)
+		},
+		"regression": func(neural *Neural32) {
+			neural.Init(WeightInitializer32FanIn, 2, 4, 1)
+			neural.EnableRegression()
+		},
+		"softmax": func(neural *Neural32) {
+			neural.Init(WeightInitializer32FanIn, 2, 4, 3)
+			neural.SetOutputActivation(Softmax32)
+		},
+		"dropout": func(neural *Neural32) {
+			neural.Init(WeightInitializer32FanIn, 2, 4, 1)
+			neural.EnableDropout(0.3)
+		},
+	}
+
+	for name, config := range configs {
+		for _, format := range []Format32{FormatGob32, FormatJSON32} {
+			t.Run(name+"/"+formatName32(format), func(t *testing.T) {
+				rand.Seed(0)
+				n := NewNeural32(config)
+
+				var buf bytes.Buffer
+				if err := n.Save(&buf, format); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+
+				got := &Neural32{}
+				if err := got.Load(&buf); err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+
+				assertNeural32Equal32(t, n, got)
+			})
+		}
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	rand.Seed(0)
+
+	n := NewNeural32(func(neural *Neural32) {
+		neural.Init(WeightInitializer32FanIn, 2, 4, 1)
+	})
+
+	path := filepath.Join(t.TempDir(), "model.gob")
+	if err := n.SaveFile(path, FormatGob32); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+
+	got := &Neural32{}
+	if err := got.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	assertNeural32Equal32(t, n, got)
+}
+
+func TestLoadRejectsBadMagic(t *testing.T) {
+	got := &Neural32{}
+	if err := got.Load(bytes.NewReader([]byte("not a model"))); err == nil {
+		t.Fatal("Load accepted a stream with no valid header")
+	}
+}
+
+func formatName32(format Format32) string {
+	if format == FormatJSON32 {
+		return "json"
+	}
+	return "gob"
+}
+
+// assertNeural32Equal32 checks that got round-tripped want's weights and
+// activation configuration, the fields state32 persists.
+func assertNeural32Equal32(t *testing.T, want, got *Neural32) {
+	t.Helper()
+
+	if len(got.Weights) != len(want.Weights) {
+		t.Fatalf("got %d weight layers, want %d", len(got.Weights), len(want.Weights))
+	}
+	for l := range want.Weights {
+		for j := range want.Weights[l] {
+			for i := range want.Weights[l][j] {
+				if got.Weights[l][j][i] != want.Weights[l][j][i] {
+					t.Fatalf("weight [%d][%d][%d] = %v, want %v", l, j, i, got.Weights[l][j][i], want.Weights[l][j][i])
+				}
+			}
+		}
+	}
+
+	for f := range want.activations {
+		if got.activations[f] != want.activations[f] {
+			t.Fatalf("activation[%d] = %q, want %q", f, got.activations[f], want.activations[f])
+		}
+	}
+	if got.softmax != want.softmax {
+		t.Fatalf("softmax = %v, want %v", got.softmax, want.softmax)
+	}
+	if got.regression != want.regression {
+		t.Fatalf("regression = %v, want %v", got.regression, want.regression)
+	}
+	if got.dropout != want.dropout {
+		t.Fatalf("dropout = %v, want %v", got.dropout, want.dropout)
+	}
+
+	input := []float32{0, 1}
+	wantContext, gotContext := want.NewContext(), got.NewContext()
+	wantContext.SetInput(input)
+	gotContext.SetInput(input)
+	wantContext.Infer()
+	gotContext.Infer()
+	for i := range wantContext.GetOutput() {
+		if wantContext.GetOutput()[i] != gotContext.GetOutput()[i] {
+			t.Fatalf("output[%d] = %v, want %v", i, gotContext.GetOutput()[i], wantContext.GetOutput()[i])
+		}
+	}
+}
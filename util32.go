@@ -34,10 +34,24 @@ func sigmoid32(x float32) float32 {
 	return 1 / (1 + float32(math.Exp(-float64(x))))
 }
 
-func dsigmoid32(y float32) float32 {
+// dsigmoid32 is the derivative of sigmoid32 with respect to the
+// pre-activation sum x.
+func dsigmoid32(x float32) float32 {
+	y := sigmoid32(x)
 	return y * (1 - y)
 }
 
+func tanh32(x float32) float32 {
+	return float32(math.Tanh(float64(x)))
+}
+
+// dtanh32 is the derivative of tanh32 with respect to the pre-activation
+// sum x.
+func dtanh32(x float32) float32 {
+	y := tanh32(x)
+	return 1 - y*y
+}
+
 func identity(x float32) float32 {
 	return x
 }